@@ -0,0 +1,152 @@
+package archive
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Downloader finds and downloads a GitHub Actions artifact by name via
+// the public REST API. Restore runs in a fresh workflow run, so it can't
+// reuse the ACTIONS_RUNTIME_* upload protocol — that's scoped to the run
+// that created the artifact — and goes through the REST API instead.
+type Downloader struct {
+	APIURL     string // e.g. https://api.github.com
+	Repository string // "owner/repo"
+	Token      string
+	HTTPClient *http.Client
+}
+
+// NewDownloader builds a Downloader against the public GitHub REST API.
+func NewDownloader(repository, token string) *Downloader {
+	return &Downloader{
+		APIURL:     "https://api.github.com",
+		Repository: repository,
+		Token:      token,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+type artifactListResponse struct {
+	Artifacts []struct {
+		Name               string `json:"name"`
+		Expired            bool   `json:"expired"`
+		ArchiveDownloadURL string `json:"archive_download_url"`
+	} `json:"artifacts"`
+}
+
+// Find looks up the most recent non-expired artifact named name.
+func (d *Downloader) Find(ctx context.Context, name string) (downloadURL string, err error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/actions/artifacts?name=%s", d.APIURL, d.Repository, url.QueryEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+d.Token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := d.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("listing artifacts: unexpected status %d", resp.StatusCode)
+	}
+
+	var parsed artifactListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", err
+	}
+	for _, a := range parsed.Artifacts {
+		if a.Name == name && !a.Expired {
+			return a.ArchiveDownloadURL, nil
+		}
+	}
+	return "", fmt.Errorf("no non-expired artifact named %q found", name)
+}
+
+// Download fetches the artifact at downloadURL. GitHub wraps every
+// artifact in a zip, which in turn contains the single name+".tar.gz"
+// file Upload wrote — Download hands back that tarball, unzipped.
+func (d *Downloader) Download(ctx context.Context, downloadURL, name string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+d.Token)
+
+	resp, err := d.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("downloading artifact: unexpected status %d", resp.StatusCode)
+	}
+
+	return unzipSingleFile(resp.Body, name+".tar.gz")
+}
+
+// unzipSingleFile spools r to a temp file rather than buffering it in
+// memory — zip.Reader needs random access (io.ReaderAt) to read the
+// central directory, which a large archived workspace shouldn't have to
+// pay for in RAM. The returned ReadCloser removes the temp file on
+// Close.
+func unzipSingleFile(r io.ReadCloser, suffix string) (io.ReadCloser, error) {
+	defer r.Close()
+
+	tmp, err := os.CreateTemp("", "workspace-archive-dl-*")
+	if err != nil {
+		return nil, err
+	}
+	removeTmp := func() { os.Remove(tmp.Name()) }
+
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		tmp.Close()
+		removeTmp()
+		return nil, err
+	}
+
+	zr, err := zip.NewReader(tmp, size)
+	if err != nil {
+		tmp.Close()
+		removeTmp()
+		return nil, fmt.Errorf("opening artifact zip: %w", err)
+	}
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, suffix) {
+			rc, err := f.Open()
+			if err != nil {
+				tmp.Close()
+				removeTmp()
+				return nil, err
+			}
+			return &tempFileCleanup{ReadCloser: rc, tmp: tmp}, nil
+		}
+	}
+	tmp.Close()
+	removeTmp()
+	return nil, fmt.Errorf("artifact zip did not contain a file ending in %q", suffix)
+}
+
+// tempFileCleanup closes the zip entry reader and then removes the
+// backing temp file that unzipSingleFile spooled the archive into.
+type tempFileCleanup struct {
+	io.ReadCloser
+	tmp *os.File
+}
+
+func (c *tempFileCleanup) Close() error {
+	err := c.ReadCloser.Close()
+	c.tmp.Close()
+	os.Remove(c.tmp.Name())
+	return err
+}
@@ -0,0 +1,196 @@
+// Package archive uploads a workspace export as a GitHub Actions
+// artifact (for "archive on delete") and later finds and downloads it
+// by name (for the companion restore mode).
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Uploader uploads a single file as a GitHub Actions artifact using the
+// same CreateArtifact → upload → PatchArtifactSize protocol
+// @actions/upload-artifact speaks, authenticated via the
+// ACTIONS_RUNTIME_TOKEN the runner injects into every job step.
+type Uploader struct {
+	RuntimeURL   string
+	RuntimeToken string
+	RunId        string
+	HTTPClient   *http.Client
+}
+
+// NewUploaderFromEnv builds an Uploader from the ACTIONS_RUNTIME_URL,
+// ACTIONS_RUNTIME_TOKEN and GITHUB_RUN_ID variables the runner sets for
+// every job step.
+func NewUploaderFromEnv() (*Uploader, error) {
+	url := os.Getenv("ACTIONS_RUNTIME_URL")
+	token := os.Getenv("ACTIONS_RUNTIME_TOKEN")
+	runId := os.Getenv("GITHUB_RUN_ID")
+	if url == "" || token == "" || runId == "" {
+		return nil, fmt.Errorf("ACTIONS_RUNTIME_URL, ACTIONS_RUNTIME_TOKEN and GITHUB_RUN_ID must be set — artifact upload only works inside a GitHub Actions job")
+	}
+	return &Uploader{
+		RuntimeURL:   strings.TrimSuffix(url, "/"),
+		RuntimeToken: token,
+		RunId:        runId,
+		HTTPClient:   http.DefaultClient,
+	}, nil
+}
+
+// Result describes an uploaded artifact.
+type Result struct {
+	Name string
+	Size int64
+	URL  string
+}
+
+// Upload streams r as a single file inside an artifact named name
+// (stored as name+".tar.gz"), with retentionDays days of retention, and
+// returns the artifact's container URL and size.
+func (u *Uploader) Upload(ctx context.Context, name string, r io.Reader, retentionDays int) (Result, error) {
+	containerURL, err := u.createArtifact(ctx, name, retentionDays)
+	if err != nil {
+		return Result{}, fmt.Errorf("create artifact: %w", err)
+	}
+
+	size, err := u.uploadBlob(ctx, containerURL, name, r)
+	if err != nil {
+		return Result{}, fmt.Errorf("upload blob: %w", err)
+	}
+
+	if err := u.finalizeArtifact(ctx, name, size); err != nil {
+		return Result{}, fmt.Errorf("finalize artifact: %w", err)
+	}
+
+	return Result{Name: name, Size: size, URL: containerURL}, nil
+}
+
+func (u *Uploader) createArtifact(ctx context.Context, name string, retentionDays int) (string, error) {
+	body, _ := json.Marshal(map[string]any{
+		"Type":          "actions_storage",
+		"Name":          name,
+		"RetentionDays": retentionDays,
+	})
+
+	endpoint := fmt.Sprintf("%s/_apis/pipelines/workflows/%s/artifacts?api-version=6.0-preview", u.RuntimeURL, u.RunId)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(string(body)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+u.RuntimeToken)
+
+	resp, err := u.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var parsed struct {
+		FileContainerResourceURL string `json:"fileContainerResourceUrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", err
+	}
+	return parsed.FileContainerResourceURL, nil
+}
+
+// uploadBlob spools r to a temp file (so a large workspace export
+// doesn't have to fit in memory) and PUTs it from there, since the
+// protocol's Content-Range header needs the total size up front.
+func (u *Uploader) uploadBlob(ctx context.Context, containerURL, name string, r io.Reader) (int64, error) {
+	tmp, err := os.CreateTemp("", "workspace-archive-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	itemPath := url.QueryEscape(name + "/" + name + ".tar.gz")
+	endpoint := fmt.Sprintf("%s?itemPath=%s", containerURL, itemPath)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, tmp)
+	if err != nil {
+		return 0, err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", size-1, size))
+	req.Header.Set("Authorization", "Bearer "+u.RuntimeToken)
+
+	resp, err := u.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return 0, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return size, nil
+}
+
+func (u *Uploader) finalizeArtifact(ctx context.Context, name string, size int64) error {
+	body, _ := json.Marshal(map[string]any{"Size": size})
+
+	endpoint := fmt.Sprintf("%s/_apis/pipelines/workflows/%s/artifacts?artifactName=%s&api-version=6.0-preview", u.RuntimeURL, u.RunId, url.QueryEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, endpoint, strings.NewReader(string(body)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+u.RuntimeToken)
+
+	resp, err := u.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// RunURL returns the GitHub Actions run URL for the current job, used to
+// build the `gh run download` restore hint.
+func RunURL() string {
+	serverURL := os.Getenv("GITHUB_SERVER_URL")
+	repository := os.Getenv("GITHUB_REPOSITORY")
+	runId := os.Getenv("GITHUB_RUN_ID")
+	return fmt.Sprintf("%s/%s/actions/runs/%s", serverURL, repository, runId)
+}
+
+// RestoreHint renders the markdown written to GITHUB_STEP_SUMMARY after
+// an archive upload: where the artifact lives and the exact command to
+// pull it back down.
+func RestoreHint(res Result) string {
+	return fmt.Sprintf(
+		"### 📦 Workspace Archived\n\n"+
+			"The workspace's state was exported to artifact `%s` (%s) before deletion.\n\n"+
+			"Run: `gh run download %s -n %s`\n\n"+
+			"Run URL: %s\n",
+		res.Name, formatBytes(res.Size), os.Getenv("GITHUB_RUN_ID"), res.Name, RunURL(),
+	)
+}
+
+func formatBytes(n int64) string {
+	return strconv.FormatInt(n, 10) + " bytes"
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// logger is the process-wide structured logger, configured once in main
+// from INPUT_LOG_LEVEL and whether we're running inside GitHub Actions.
+// Never log cfg.token, env var values, or anything else derived from
+// INPUT_* secrets — only stable identifiers like workspace ids and stage
+// names belong in structured fields.
+var logger zerolog.Logger
+
+// setupLogger builds the process logger: a human-friendly console writer
+// when running inside GitHub Actions (GITHUB_ACTIONS=true), JSON
+// otherwise so log aggregators can parse it. Debug-level events are only
+// written when ACTIONS_STEP_DEBUG=true, and are prefixed with the
+// `::debug::` workflow command so they land in the collapsed debug log.
+func setupLogger(levelStr string) zerolog.Logger {
+	level, err := zerolog.ParseLevel(strings.ToLower(levelStr))
+	if err != nil {
+		level = zerolog.InfoLevel
+	}
+
+	// zerolog's own .Level(level) filter runs before debugGatedWriter
+	// ever sees an event, so debug events need the logger's level
+	// lowered to Debug, not just the writer unlocked, for
+	// ACTIONS_STEP_DEBUG=true to actually surface them.
+	debugEnabled := os.Getenv("ACTIONS_STEP_DEBUG") == "true"
+	if debugEnabled && level > zerolog.DebugLevel {
+		level = zerolog.DebugLevel
+	}
+
+	var w io.Writer = os.Stdout
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		w = zerolog.ConsoleWriter{Out: os.Stdout, NoColor: true}
+	}
+	w = debugGatedWriter{out: w, enabled: debugEnabled}
+
+	return zerolog.New(w).Level(level).With().Timestamp().Logger()
+}
+
+// debugGatedWriter implements zerolog.LevelWriter so debug-level events
+// can be suppressed or routed through the `::debug::` workflow command
+// independently of the configured log level.
+type debugGatedWriter struct {
+	out     io.Writer
+	enabled bool
+}
+
+func (w debugGatedWriter) Write(p []byte) (int, error) {
+	return w.out.Write(p)
+}
+
+func (w debugGatedWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level != zerolog.DebugLevel {
+		return w.out.Write(p)
+	}
+	if !w.enabled {
+		return len(p), nil
+	}
+	if _, err := io.WriteString(w.out, "::debug::"); err != nil {
+		return 0, err
+	}
+	return w.out.Write(p)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/codesphere-cloud/cs-go/api"
+)
+
+// defaultMaxLogBytes caps how much of a stage's log output we forward into
+// the Actions log when INPUT_MAX_LOG_BYTES is not set.
+const defaultMaxLogBytes = 4 * 1024 * 1024 // 4 MiB
+
+// stageLogPollInterval is how often stageLogPoller re-fetches a stage's
+// logs while streamStageLogs is pumping them to stdout.
+const stageLogPollInterval = 2 * time.Second
+
+// stageLogStreamer pumps a stage's log reader into stdout, prefixing every
+// line with the stage name and a timestamp, while keeping a copy of every
+// line it forwards so it can be mirrored into GITHUB_STEP_SUMMARY once the
+// stage finishes. The copy holds the same lines as stdout: the first
+// maxBytes of the stage's log, not only its last lines.
+type stageLogStreamer struct {
+	stage    string
+	maxBytes int64
+	tail     []byte
+}
+
+// stageLogPoller adapts GetLogsOfStage into an io.Reader. The Codesphere
+// API has no push/stream endpoint for stage logs — GetLogsOfStage just
+// returns the full list of lines emitted so far — so this polls on an
+// interval and only emits the lines it hasn't already returned.
+type stageLogPoller struct {
+	ctx    context.Context
+	client *api.Client
+	wsId   int
+	stage  string
+
+	seen int
+	buf  bytes.Buffer
+}
+
+func newStageLogPoller(ctx context.Context, client *api.Client, wsId int, stage string) *stageLogPoller {
+	return &stageLogPoller{ctx: ctx, client: client, wsId: wsId, stage: stage}
+}
+
+// poll fetches the stage's log lines and appends any new ones to buf.
+func (p *stageLogPoller) poll() error {
+	resp, err := p.client.GetLogsOfStage(p.wsId, p.stage, 0)
+	if err != nil {
+		return fmt.Errorf("fetching logs for stage %q: %w", p.stage, err)
+	}
+	if resp == nil || resp.WorkspacesLogsGetResponse == nil {
+		return nil
+	}
+
+	entries := resp.WorkspacesLogsGetResponse.Data
+	start := p.seen
+	if start > len(entries) {
+		start = len(entries)
+	}
+	for _, e := range entries[start:] {
+		fmt.Fprintf(&p.buf, "%s\n", e.Data)
+	}
+	p.seen = len(entries)
+	return nil
+}
+
+// Read implements io.Reader, blocking until poll turns up new lines or
+// ctx is cancelled.
+func (p *stageLogPoller) Read(b []byte) (int, error) {
+	for p.buf.Len() == 0 {
+		select {
+		case <-p.ctx.Done():
+			return 0, io.EOF
+		default:
+		}
+
+		if err := p.poll(); err != nil {
+			return 0, err
+		}
+		if p.buf.Len() > 0 {
+			break
+		}
+
+		select {
+		case <-p.ctx.Done():
+			return 0, io.EOF
+		case <-time.After(stageLogPollInterval):
+		}
+	}
+	return p.buf.Read(b)
+}
+
+// streamStageLogs polls stage's logs and pumps them to stdout until ctx
+// is cancelled or the poller errors out. It returns a channel that is
+// closed once streaming has stopped, and writes a collapsed <details>
+// block to GITHUB_STEP_SUMMARY holding every line it forwarded.
+func streamStageLogs(ctx context.Context, client *api.Client, wsId int, stage string, maxBytes int64) <-chan struct{} {
+	done := make(chan struct{})
+
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxLogBytes
+	}
+
+	go func() {
+		defer close(done)
+
+		poller := newStageLogPoller(ctx, client, wsId, stage)
+		if err := poller.poll(); err != nil {
+			logger.Warn().Err(err).Str("stage", stage).Msg("could not open logs for stage")
+			return
+		}
+
+		s := &stageLogStreamer{stage: stage, maxBytes: maxBytes}
+		s.pump(io.LimitReader(poller, maxBytes))
+		s.writeSummary()
+	}()
+
+	return done
+}
+
+func (s *stageLogStreamer) pump(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		ts := time.Now().Format("15:04:05")
+		prefixed := fmt.Sprintf("[%s] %s %s\n", s.stage, ts, line)
+		fmt.Print(prefixed)
+		s.tail = append(s.tail, prefixed...)
+	}
+	// scanner.Err() is nil on EOF and on the poller returning io.EOF via
+	// ctx cancellation — the stage poller already knows why.
+}
+
+func (s *stageLogStreamer) writeSummary() {
+	f := os.Getenv("GITHUB_STEP_SUMMARY")
+	if f == "" || len(s.tail) == 0 {
+		return
+	}
+	appendToFile(f, fmt.Sprintf(
+		"<details><summary>Logs: %s</summary>\n\n```\n%s\n```\n\n</details>\n\n",
+		s.stage, s.tail,
+	))
+}
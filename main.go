@@ -11,6 +11,10 @@ import (
 	"time"
 
 	"github.com/codesphere-cloud/cs-go/api"
+
+	"github.com/alexvcodesphere/new-gh-action-test/archive"
+	"github.com/alexvcodesphere/new-gh-action-test/pipeline"
+	"github.com/alexvcodesphere/new-gh-action-test/retry"
 )
 
 // ---------------------------------------------------------------------------
@@ -18,14 +22,28 @@ import (
 // ---------------------------------------------------------------------------
 
 type config struct {
-	apiUrl    *url.URL
-	token     string
-	teamId    int
-	planId    int
-	envVars   map[string]string
-	vpnConfig string
-	branch    string
-	stages    []string
+	apiUrl      *url.URL
+	token       string
+	teamId      int
+	planId      int
+	envVars     map[string]string
+	vpnConfig   string
+	branch      string
+	stagesRaw   string
+	maxLogBytes int64
+	retryPolicy retry.Policy
+	logLevel    string
+	maxParallel int
+
+	mode                 string
+	scaletestConcurrency int
+	scaletestCount       int
+	scaletestTimeout     time.Duration
+
+	archiveOnDelete      bool
+	archiveRetentionDays int
+	archiveName          string
+	githubToken          string
 
 	repoUrl    string
 	repository string
@@ -40,14 +58,36 @@ func loadConfig() config {
 	apiUrl, _ := url.Parse(env("INPUT_APIURL", "https://codesphere.com/api"))
 	teamId, _ := strconv.Atoi(env("INPUT_TEAMID", "0"))
 	planId, _ := strconv.Atoi(env("INPUT_PLANID", "8"))
+	maxLogBytes, err := strconv.ParseInt(env("INPUT_MAX_LOG_BYTES", ""), 10, 64)
+	if err != nil || maxLogBytes <= 0 {
+		maxLogBytes = defaultMaxLogBytes
+	}
 
-	// Parse stages
-	stagesStr := env("INPUT_STAGES", "prepare run")
-	var stages []string
-	for _, s := range strings.Fields(stagesStr) {
-		if s != "" {
-			stages = append(stages, s)
-		}
+	retryLimit, err := strconv.Atoi(env("INPUT_RETRY_LIMIT", ""))
+	if err != nil || retryLimit <= 0 {
+		retryLimit = retry.DefaultPolicy.Limit
+	}
+	retryBackoff, err := time.ParseDuration(env("INPUT_RETRY_BACKOFF", ""))
+	if err != nil || retryBackoff <= 0 {
+		retryBackoff = retry.DefaultPolicy.Backoff
+	}
+
+	scaletestConcurrency, _ := strconv.Atoi(env("INPUT_SCALETEST_CONCURRENCY", "5"))
+	scaletestCount, _ := strconv.Atoi(env("INPUT_SCALETEST_COUNT", "10"))
+	scaletestTimeout, err := time.ParseDuration(env("INPUT_SCALETEST_TIMEOUT", ""))
+	if err != nil || scaletestTimeout <= 0 {
+		scaletestTimeout = 15 * time.Minute
+	}
+
+	stagesRaw := env("INPUT_STAGES", "prepare run")
+	maxParallel, _ := strconv.Atoi(env("INPUT_MAX_PARALLEL_STAGES", "4"))
+
+	archiveOnDelete, _ := strconv.ParseBool(env("INPUT_ARCHIVE_ON_DELETE", "false"))
+	archiveRetentionDays, _ := strconv.Atoi(env("INPUT_ARCHIVE_RETENTION_DAYS", "14"))
+
+	githubToken := os.Getenv("INPUT_GITHUB_TOKEN")
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN")
 	}
 
 	// Parse env vars (KEY=VALUE per line)
@@ -63,14 +103,28 @@ func loadConfig() config {
 	prAction, prNumber := loadGitHubEvent()
 
 	return config{
-		apiUrl:    apiUrl,
-		token:     os.Getenv("INPUT_TOKEN"),
-		teamId:    teamId,
-		planId:    planId,
-		envVars:   envVars,
-		vpnConfig: os.Getenv("INPUT_VPNCONFIG"),
-		branch:    os.Getenv("INPUT_BRANCH"),
-		stages:    stages,
+		apiUrl:      apiUrl,
+		token:       os.Getenv("INPUT_TOKEN"),
+		teamId:      teamId,
+		planId:      planId,
+		envVars:     envVars,
+		vpnConfig:   os.Getenv("INPUT_VPNCONFIG"),
+		branch:      os.Getenv("INPUT_BRANCH"),
+		stagesRaw:   stagesRaw,
+		maxLogBytes: maxLogBytes,
+		retryPolicy: retry.Policy{Limit: retryLimit, Backoff: retryBackoff},
+		logLevel:    env("INPUT_LOG_LEVEL", "info"),
+		maxParallel: maxParallel,
+
+		mode:                 env("INPUT_MODE", "deploy"),
+		scaletestConcurrency: scaletestConcurrency,
+		scaletestCount:       scaletestCount,
+		scaletestTimeout:     scaletestTimeout,
+
+		archiveOnDelete:      archiveOnDelete,
+		archiveRetentionDays: archiveRetentionDays,
+		archiveName:          env("INPUT_ARCHIVE_NAME", ""),
+		githubToken:          githubToken,
 
 		repoUrl:    fmt.Sprintf("%s/%s.git", os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY")),
 		repository: os.Getenv("GITHUB_REPOSITORY"),
@@ -134,18 +188,23 @@ func (c *config) resolveBranch() string {
 // Workspace operations
 // ---------------------------------------------------------------------------
 
-func findWorkspace(client *api.Client, cfg *config) (*api.Workspace, error) {
+func findWorkspace(ctx context.Context, client *api.Client, cfg *config) (*api.Workspace, error) {
 	name := cfg.workspaceName()
-	fmt.Printf("🔍 Looking for workspace '%s'...\n", name)
+	logger.Info().Str("name", name).Msg("looking for workspace")
 
-	workspaces, err := client.ListWorkspaces(cfg.teamId)
+	var workspaces []api.Workspace
+	err := retry.Do(ctx, cfg.retryPolicy, func() error {
+		var err error
+		workspaces, err = client.ListWorkspaces(cfg.teamId)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("listing workspaces: %w", err)
 	}
 
 	for i := range workspaces {
 		if workspaces[i].Name == name {
-			fmt.Printf("  Found: id=%d\n", workspaces[i].Id)
+			logger.Info().Int("ws", workspaces[i].Id).Msg("workspace found")
 			return &workspaces[i], nil
 		}
 	}
@@ -154,7 +213,7 @@ func findWorkspace(client *api.Client, cfg *config) (*api.Workspace, error) {
 
 func createWorkspace(client *api.Client, cfg *config, branch string) (*api.Workspace, error) {
 	name := cfg.workspaceName()
-	fmt.Printf("🚀 Creating workspace '%s'...\n", name)
+	logger.Info().Str("name", name).Msg("creating workspace")
 
 	ws, err := client.DeployWorkspace(api.DeployWorkspaceArgs{
 		TeamId:        cfg.teamId,
@@ -171,30 +230,63 @@ func createWorkspace(client *api.Client, cfg *config, branch string) (*api.Works
 		return nil, fmt.Errorf("creating workspace: %w", err)
 	}
 
-	fmt.Printf("  Created: id=%d\n", ws.Id)
+	logger.Info().Int("ws", ws.Id).Msg("workspace created")
 	return ws, nil
 }
 
 func deleteWorkspace(client *api.Client, wsId int) error {
-	fmt.Printf("🗑️  Deleting workspace %d...\n", wsId)
+	logger.Info().Int("ws", wsId).Msg("deleting workspace")
 	return client.DeleteWorkspace(wsId)
 }
 
-func updateWorkspace(client *api.Client, cfg *config, ws *api.Workspace, branch string) error {
-	fmt.Println("  ⏰ Waiting for workspace to be running...")
+// archiveWorkspace exports wsId's state and uploads it as a GitHub Actions
+// artifact before the workspace is deleted, so it can be brought back later
+// with the companion "restore" mode.
+func archiveWorkspace(ctx context.Context, client *api.Client, cfg *config, wsId int) error {
+	logger.Info().Int("ws", wsId).Msg("exporting workspace before delete")
+
+	export, err := exportWorkspace(client, wsId)
+	if err != nil {
+		return fmt.Errorf("exporting workspace: %w", err)
+	}
+
+	uploader, err := archive.NewUploaderFromEnv()
+	if err != nil {
+		return fmt.Errorf("preparing artifact upload: %w", err)
+	}
+
+	name := fmt.Sprintf("workspace-archive-%s", cfg.workspaceName())
+	result, err := uploader.Upload(ctx, name, export, cfg.archiveRetentionDays)
+	if err != nil {
+		return fmt.Errorf("uploading archive: %w", err)
+	}
+	logger.Info().Str("artifact", result.Name).Int64("bytes", result.Size).Msg("workspace archived")
+
+	if f := os.Getenv("GITHUB_STEP_SUMMARY"); f != "" {
+		appendToFile(f, archive.RestoreHint(result))
+	}
+	return nil
+}
+
+func updateWorkspace(ctx context.Context, client *api.Client, cfg *config, ws *api.Workspace, branch string) error {
+	logger.Info().Int("ws", ws.Id).Msg("waiting for workspace to be running")
 	if err := client.WaitForWorkspaceRunning(ws, 5*time.Minute); err != nil {
 		return err
 	}
-	fmt.Println("  ✅ Workspace is running.")
+	logger.Info().Int("ws", ws.Id).Msg("workspace is running")
 
-	fmt.Printf("  📥 Pulling branch '%s'...\n", branch)
-	if err := client.GitPull(ws.Id, "origin", branch); err != nil {
+	logger.Info().Int("ws", ws.Id).Str("branch", branch).Msg("pulling branch")
+	if err := retry.Do(ctx, cfg.retryPolicy, func() error {
+		return client.GitPull(ws.Id, "origin", branch)
+	}); err != nil {
 		return fmt.Errorf("git pull: %w", err)
 	}
 
 	if len(cfg.envVars) > 0 {
-		fmt.Printf("  🔧 Setting %d environment variable(s)...\n", len(cfg.envVars))
-		if err := client.SetEnvVarOnWorkspace(ws.Id, cfg.envVars); err != nil {
+		logger.Info().Int("ws", ws.Id).Int("count", len(cfg.envVars)).Msg("setting environment variables")
+		if err := retry.Do(ctx, cfg.retryPolicy, func() error {
+			return client.SetEnvVarOnWorkspace(ws.Id, cfg.envVars)
+		}); err != nil {
 			return fmt.Errorf("setting env vars: %w", err)
 		}
 	}
@@ -206,53 +298,121 @@ func updateWorkspace(client *api.Client, cfg *config, ws *api.Workspace, branch
 // Pipeline
 // ---------------------------------------------------------------------------
 
-func runPipeline(client *api.Client, wsId int, stages []string) error {
-	if len(stages) == 0 {
+// runPipeline parses rawStages into a DAG (falling back to a linear chain
+// for a plain space-separated list) and schedules it, running everything
+// that can run concurrently up to maxParallel at once.
+func runPipeline(ctx context.Context, client *api.Client, wsId int, rawStages string, maxLogBytes int64, policy retry.Policy, maxParallel int) error {
+	dag, err := pipeline.ParseStages(rawStages)
+	if err != nil {
+		return err
+	}
+	if len(dag.Stages) == 0 {
 		return nil
 	}
 
-	fmt.Printf("🔧 Running pipeline: %s\n", strings.Join(stages, " → "))
+	logger.Info().Int("stages", len(dag.Stages)).Msg("running pipeline")
 
-	for _, stage := range stages {
-		fmt.Printf("  ▶ Starting '%s'...\n", stage)
-		if err := client.StartPipelineStage(wsId, "", stage); err != nil {
-			return fmt.Errorf("starting stage '%s': %w", stage, err)
-		}
+	return pipeline.Run(ctx, dag, maxParallel, func(ctx context.Context, stage string) error {
+		return runStage(ctx, client, wsId, stage, maxLogBytes, policy)
+	})
+}
 
-		// 'run' is fire-and-forget
-		if stage == "run" {
-			fmt.Printf("  ✅ '%s' triggered.\n", stage)
-			continue
+// runStage starts a single stage and, unless it's the fire-and-forget
+// terminal 'run' stage, streams its logs and polls until it reaches a
+// terminal state.
+func runStage(ctx context.Context, client *api.Client, wsId int, stage string, maxLogBytes int64, policy retry.Policy) error {
+	logger.Info().Str("stage", stage).Int("ws", wsId).Msg("stage started")
+	if err := retry.Do(ctx, policy, func() error {
+		return client.StartPipelineStage(wsId, "", stage)
+	}); err != nil {
+		return fmt.Errorf("starting stage '%s': %w", stage, err)
+	}
+
+	// 'run' is fire-and-forget
+	if stage == "run" {
+		logger.Info().Str("stage", stage).Int("ws", wsId).Msg("stage triggered")
+		return nil
+	}
+
+	// Stream the stage's logs alongside polling; cancelling stageCtx
+	// tears down the reader as soon as we stop polling this stage.
+	stageCtx, cancelStage := context.WithCancel(ctx)
+	logsDone := streamStageLogs(stageCtx, client, wsId, stage, maxLogBytes)
+
+	stageErr := pollStage(stageCtx, client, wsId, stage, policy)
+
+	cancelStage()
+	<-logsDone
+
+	return stageErr
+}
+
+// pollStage polls GetPipelineState until the stage reaches a terminal
+// state. There is no fixed deadline; instead a background goroutine
+// pings the workspace every minute for as long as the stage is
+// executing, so any reverse-proxy idle timeout sitting in front of the
+// API sees regular traffic and doesn't cut the workspace off mid-stage.
+func pollStage(ctx context.Context, client *api.Client, wsId int, stage string, policy retry.Policy) error {
+	keepaliveCtx, cancelKeepalive := context.WithCancel(ctx)
+	defer cancelKeepalive()
+	go keepaliveWorkspace(keepaliveCtx, client, wsId, stage)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
 		}
 
-		// Poll until done
-		deadline := time.Now().Add(30 * time.Minute)
-		for time.Now().Before(deadline) {
-			time.Sleep(5 * time.Second)
-			statuses, err := client.GetPipelineState(wsId, stage)
-			if err != nil {
-				continue // transient error, retry
+		var statuses []api.PipelineStatus
+		err := retry.Do(ctx, policy, func() error {
+			var err error
+			statuses, err = client.GetPipelineState(wsId, stage)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("polling stage '%s': %w", stage, err)
+		}
+		logger.Debug().Str("stage", stage).Int("ws", wsId).Int("steps", len(statuses)).Msg("poll tick")
+
+		allDone := true
+		for _, s := range statuses {
+			switch s.State {
+			case "failure", "aborted":
+				return fmt.Errorf("pipeline '%s' failed (state: %s)", stage, s.State)
+			case "success":
+				// good
+			default:
+				allDone = false
 			}
+		}
 
-			allDone := true
-			for _, s := range statuses {
-				switch s.State {
-				case "failure", "aborted":
-					return fmt.Errorf("pipeline '%s' failed (state: %s)", stage, s.State)
-				case "success":
-					// good
-				default:
-					allDone = false
-				}
-			}
+		if allDone && len(statuses) > 0 {
+			logger.Info().Str("stage", stage).Int("ws", wsId).Msg("stage completed")
+			return nil
+		}
+	}
+}
 
-			if allDone && len(statuses) > 0 {
-				fmt.Printf("  ✅ '%s' completed.\n", stage)
-				break
+// keepaliveWorkspace pings the workspace every minute for as long as a
+// stage is still executing. The API has no lease/extend endpoint to
+// call, so WorkspaceStatus — a cheap, idempotent, already-real
+// read-only call — stands in for it. Ping failures are logged, not
+// swallowed — a string of them signals the workspace may already be
+// gone.
+func keepaliveWorkspace(ctx context.Context, client *api.Client, wsId int, stage string) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := client.WorkspaceStatus(wsId); err != nil {
+				logger.Warn().Err(err).Str("stage", stage).Int("ws", wsId).Msg("keepalive ping failed")
 			}
 		}
 	}
-	return nil
 }
 
 // ---------------------------------------------------------------------------
@@ -261,7 +421,7 @@ func runPipeline(client *api.Client, wsId int, stages []string) error {
 
 func setOutputs(wsId int) {
 	url := fmt.Sprintf("https://%d-3000.2.codesphere.com/", wsId)
-	fmt.Printf("🔗 Deployment URL: %s\n", url)
+	logger.Info().Int("ws", wsId).Str("url", url).Msg("deployment url ready")
 
 	if f := os.Getenv("GITHUB_OUTPUT"); f != "" {
 		appendToFile(f, fmt.Sprintf("deployment-url=%s\nworkspace-id=%d\n", url, wsId))
@@ -296,7 +456,7 @@ func appendToFile(path, content string) {
 }
 
 func fatal(msg string, err error) {
-	fmt.Fprintf(os.Stderr, "❌ %s: %v\n", msg, err)
+	logger.Error().Err(err).Msg(msg)
 	os.Exit(1)
 }
 
@@ -306,55 +466,77 @@ func fatal(msg string, err error) {
 
 func main() {
 	cfg := loadConfig()
-	branch := cfg.resolveBranch()
-	fmt.Printf("🌿 Target branch: %s\n", branch)
+	logger = setupLogger(cfg.logLevel)
 
-	client := api.NewClient(context.Background(), api.Configuration{
+	ctx := context.Background()
+	client := api.NewClient(ctx, api.Configuration{
 		BaseUrl: cfg.apiUrl,
 		Token:   cfg.token,
 	})
 
+	if cfg.mode == "scaletest" {
+		if err := runScaletest(ctx, client, &cfg); err != nil {
+			fatal("running scaletest", err)
+		}
+		return
+	}
+
+	if cfg.mode == "restore" {
+		if err := runRestore(ctx, client, &cfg); err != nil {
+			fatal("restoring workspace", err)
+		}
+		return
+	}
+
+	branch := cfg.resolveBranch()
+	logger.Info().Str("branch", branch).Msg("target branch resolved")
+
 	// PR closed → delete workspace
 	if cfg.eventName == "pull_request" && cfg.prAction == "closed" {
-		ws, err := findWorkspace(client, &cfg)
+		ws, err := findWorkspace(ctx, client, &cfg)
 		if err != nil {
 			fatal("finding workspace", err)
 		}
 		if ws != nil {
+			if cfg.archiveOnDelete {
+				if err := archiveWorkspace(ctx, client, &cfg, ws.Id); err != nil {
+					fatal("archiving workspace", err)
+				}
+			}
 			if err := deleteWorkspace(client, ws.Id); err != nil {
 				fatal("deleting workspace", err)
 			}
-			fmt.Println("✅ Workspace deleted.")
+			logger.Info().Int("ws", ws.Id).Msg("workspace deleted")
 		} else {
-			fmt.Println("ℹ️  No workspace found — nothing to delete.")
+			logger.Info().Msg("no workspace found, nothing to delete")
 		}
 		return
 	}
 
 	// PR opened/updated → create or update
-	existing, err := findWorkspace(client, &cfg)
+	existing, err := findWorkspace(ctx, client, &cfg)
 	if err != nil {
 		fatal("finding workspace", err)
 	}
 
 	if existing != nil {
-		if err := updateWorkspace(client, &cfg, existing, branch); err != nil {
+		if err := updateWorkspace(ctx, client, &cfg, existing, branch); err != nil {
 			fatal("updating workspace", err)
 		}
 		setOutputs(existing.Id)
-		if err := runPipeline(client, existing.Id, cfg.stages); err != nil {
+		if err := runPipeline(ctx, client, existing.Id, cfg.stagesRaw, cfg.maxLogBytes, cfg.retryPolicy, cfg.maxParallel); err != nil {
 			fatal("running pipeline", err)
 		}
-		fmt.Printf("✅ Workspace %d updated.\n", existing.Id)
+		logger.Info().Int("ws", existing.Id).Msg("workspace updated")
 	} else {
 		ws, err := createWorkspace(client, &cfg, branch)
 		if err != nil {
 			fatal("creating workspace", err)
 		}
 		setOutputs(ws.Id)
-		if err := runPipeline(client, ws.Id, cfg.stages); err != nil {
+		if err := runPipeline(ctx, client, ws.Id, cfg.stagesRaw, cfg.maxLogBytes, cfg.retryPolicy, cfg.maxParallel); err != nil {
 			fatal("running pipeline", err)
 		}
-		fmt.Println("✅ New workspace created.")
+		logger.Info().Int("ws", ws.Id).Msg("new workspace created")
 	}
 }
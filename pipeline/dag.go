@@ -0,0 +1,151 @@
+// Package pipeline parses INPUT_STAGES into a stage dependency graph and
+// schedules stages for execution, running everything that can run
+// concurrently while honoring "needs" edges.
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Stage is one node in the pipeline DAG: its name and the stages it
+// depends on.
+type Stage struct {
+	Name  string   `json:"name"`
+	Needs []string `json:"needs,omitempty"`
+}
+
+// DAG is the parsed form of INPUT_STAGES.
+type DAG struct {
+	Stages []Stage `json:"stages"`
+}
+
+// ParseStages parses INPUT_STAGES. A value starting with '{' is parsed
+// as a DAG object (e.g. {"stages":[{"name":"test","needs":["lint"]}]});
+// only the JSON subset of YAML is accepted today, which already covers
+// every example in the action's docs. Anything else is treated the way
+// it always has been: a whitespace-separated linear chain, where each
+// stage needs the one before it.
+func ParseStages(raw string) (DAG, error) {
+	trimmed := strings.TrimSpace(raw)
+	if strings.HasPrefix(trimmed, "{") {
+		var dag DAG
+		if err := json.Unmarshal([]byte(trimmed), &dag); err != nil {
+			return DAG{}, fmt.Errorf("parsing stage DAG: %w", err)
+		}
+		if err := dag.Validate(); err != nil {
+			return DAG{}, err
+		}
+		return dag, nil
+	}
+
+	var stages []Stage
+	var prev string
+	for _, name := range strings.Fields(trimmed) {
+		s := Stage{Name: name}
+		if prev != "" {
+			s.Needs = []string{prev}
+		}
+		stages = append(stages, s)
+		prev = name
+	}
+	dag := DAG{Stages: stages}
+	if err := dag.Validate(); err != nil {
+		return DAG{}, err
+	}
+	return dag, nil
+}
+
+// Validate checks that every "needs" edge names a real stage, that the
+// graph is acyclic (via Kahn's algorithm), and that "run" — if present —
+// is the DAG's unique terminal stage, since it keeps its fire-and-forget
+// semantics and nothing can depend on its (never awaited) completion.
+func (d DAG) Validate() error {
+	names := make(map[string]bool, len(d.Stages))
+	for _, s := range d.Stages {
+		names[s.Name] = true
+	}
+	for _, s := range d.Stages {
+		for _, need := range s.Needs {
+			if !names[need] {
+				return fmt.Errorf("stage %q needs unknown stage %q", s.Name, need)
+			}
+		}
+	}
+
+	inDegree := make(map[string]int, len(d.Stages))
+	dependents := make(map[string][]string)
+	for _, s := range d.Stages {
+		inDegree[s.Name] += len(s.Needs)
+		for _, need := range s.Needs {
+			dependents[need] = append(dependents[need], s.Name)
+		}
+	}
+
+	queue := make([]string, 0, len(d.Stages))
+	for _, s := range d.Stages {
+		if inDegree[s.Name] == 0 {
+			queue = append(queue, s.Name)
+		}
+	}
+
+	visited := 0
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		visited++
+		for _, dep := range dependents[n] {
+			inDegree[dep]--
+			if inDegree[dep] == 0 {
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	if visited != len(d.Stages) {
+		for _, s := range d.Stages {
+			if inDegree[s.Name] > 0 {
+				for _, need := range s.Needs {
+					if inDegree[need] > 0 {
+						return fmt.Errorf("stage DAG has a cycle: %q needs %q", s.Name, need)
+					}
+				}
+			}
+		}
+		return fmt.Errorf("stage DAG has a cycle")
+	}
+
+	// Only a DAG containing "run" is constrained to a single terminal
+	// stage — "run" is fire-and-forget, so its completion is never
+	// awaited, and nothing can meaningfully depend on it finishing.
+	// DAGs without "run" are free to have multiple independent sinks.
+	if names["run"] {
+		sinks := d.sinks()
+		if len(sinks) > 1 {
+			return fmt.Errorf("stage DAG containing %q must have a single terminal stage, found %d: %v", "run", len(sinks), sinks)
+		}
+		if len(sinks) == 1 && sinks[0] != "run" {
+			return fmt.Errorf("%q must be the unique terminal stage, got %q", "run", sinks[0])
+		}
+	}
+
+	return nil
+}
+
+// sinks returns the stages nothing depends on (out-degree zero).
+func (d DAG) sinks() []string {
+	hasDependent := make(map[string]bool, len(d.Stages))
+	for _, s := range d.Stages {
+		for _, need := range s.Needs {
+			hasDependent[need] = true
+		}
+	}
+	var sinks []string
+	for _, s := range d.Stages {
+		if !hasDependent[s.Name] {
+			sinks = append(sinks, s.Name)
+		}
+	}
+	return sinks
+}
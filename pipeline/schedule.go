@@ -0,0 +1,101 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+)
+
+// Run schedules dag's stages for execution, launching every
+// zero-dependency stage immediately and each stage as soon as all the
+// stages it needs have finished, up to maxParallel running at once.
+// exec is called once per stage, synchronously from a dedicated
+// goroutine. On the first error, the shared ctx is cancelled so
+// in-flight exec calls can return early and stages that haven't started
+// yet are skipped instead of launched.
+func Run(ctx context.Context, dag DAG, maxParallel int, exec func(ctx context.Context, stage string) error) error {
+	if err := dag.Validate(); err != nil {
+		return err
+	}
+	if len(dag.Stages) == 0 {
+		return nil
+	}
+	if maxParallel <= 0 {
+		maxParallel = len(dag.Stages)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	inDegree := make(map[string]int, len(dag.Stages))
+	dependents := make(map[string][]string)
+	for _, s := range dag.Stages {
+		inDegree[s.Name] = len(s.Needs)
+		for _, need := range s.Needs {
+			dependents[need] = append(dependents[need], s.Name)
+		}
+	}
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, maxParallel)
+
+	var schedule func(name string)
+	schedule = func(name string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case <-ctx.Done():
+				return
+			case sem <- struct{}{}:
+			}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err := exec(ctx, name); err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+
+			mu.Lock()
+			var ready []string
+			for _, dep := range dependents[name] {
+				inDegree[dep]--
+				if inDegree[dep] == 0 {
+					ready = append(ready, dep)
+				}
+			}
+			mu.Unlock()
+
+			for _, dep := range ready {
+				schedule(dep)
+			}
+		}()
+	}
+
+	// Compute the whole initial ready set before scheduling any of it —
+	// schedule() mutates inDegree from other goroutines as soon as it
+	// runs, so this loop must finish reading before that starts.
+	var initialReady []string
+	for _, s := range dag.Stages {
+		if inDegree[s.Name] == 0 {
+			initialReady = append(initialReady, s.Name)
+		}
+	}
+	for _, name := range initialReady {
+		schedule(name)
+	}
+
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/codesphere-cloud/cs-go/api"
+
+	"github.com/alexvcodesphere/new-gh-action-test/archive"
+)
+
+// runRestore is the companion to archiveWorkspace: given cfg.archiveName, it
+// finds that artifact via the GitHub REST API, re-creates the workspace and
+// streams the archived tarball back in before running the usual pipeline.
+func runRestore(ctx context.Context, client *api.Client, cfg *config) error {
+	if cfg.archiveName == "" {
+		return fmt.Errorf("INPUT_ARCHIVE_NAME must be set in restore mode")
+	}
+	if cfg.githubToken == "" {
+		return fmt.Errorf("INPUT_GITHUB_TOKEN (or GITHUB_TOKEN) must be set in restore mode")
+	}
+
+	logger.Info().Str("artifact", cfg.archiveName).Msg("looking up archive")
+
+	downloader := archive.NewDownloader(cfg.repository, cfg.githubToken)
+	downloadURL, err := downloader.Find(ctx, cfg.archiveName)
+	if err != nil {
+		return fmt.Errorf("finding archive: %w", err)
+	}
+
+	rc, err := downloader.Download(ctx, downloadURL, cfg.archiveName)
+	if err != nil {
+		return fmt.Errorf("downloading archive: %w", err)
+	}
+	defer rc.Close()
+
+	branch := cfg.resolveBranch()
+	ws, err := createWorkspace(client, cfg, branch)
+	if err != nil {
+		return fmt.Errorf("creating workspace: %w", err)
+	}
+
+	logger.Info().Int("ws", ws.Id).Str("artifact", cfg.archiveName).Msg("importing archived state")
+	if err := importWorkspace(client, ws.Id, rc); err != nil {
+		return fmt.Errorf("importing workspace: %w", err)
+	}
+
+	setOutputs(ws.Id)
+	if err := runPipeline(ctx, client, ws.Id, cfg.stagesRaw, cfg.maxLogBytes, cfg.retryPolicy, cfg.maxParallel); err != nil {
+		return fmt.Errorf("running pipeline: %w", err)
+	}
+
+	logger.Info().Int("ws", ws.Id).Msg("workspace restored")
+	if f := os.Getenv("GITHUB_STEP_SUMMARY"); f != "" {
+		appendToFile(f, fmt.Sprintf("### ♻️ Workspace Restored\n\nRestored from artifact `%s` into workspace `%d`.\n", cfg.archiveName, ws.Id))
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Package retry provides exponential backoff with jitter around flaky API
+// calls, distinguishing transient errors worth retrying from terminal ones
+// that should fail fast.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Policy controls how many times Do retries a failing operation and how
+// long it waits between attempts.
+type Policy struct {
+	Limit   int           // max attempts, including the first
+	Backoff time.Duration // base backoff before exponential growth + jitter
+}
+
+// DefaultPolicy mirrors the action's default inputs:
+// INPUT_RETRY_LIMIT=10, INPUT_RETRY_BACKOFF=15s.
+var DefaultPolicy = Policy{Limit: 10, Backoff: 15 * time.Second}
+
+// StatusCoder is implemented by errors that carry an HTTP status code
+// directly. api.Client's errors don't satisfy this — errors.FormatAPIError
+// bakes the status into the message string instead of exposing it on the
+// error type — but it's kept for any error that does.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// apiStatusCodeRe extracts the HTTP status code api.Client's
+// errors.FormatAPIError bakes into every error message, e.g.
+// "codesphere API returned error 429 (...)" or
+// "unexpected error 502 at URL ...". This is the only way to recover the
+// status code: FormatAPIError discards the *http.Response before
+// returning.
+var apiStatusCodeRe = regexp.MustCompile(`\berror (\d{3})\b`)
+
+func apiStatusCode(err error) (int, bool) {
+	m := apiStatusCodeRe.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0, false
+	}
+	code, convErr := strconv.Atoi(m[1])
+	if convErr != nil {
+		return 0, false
+	}
+	return code, true
+}
+
+// RetryAfterer is implemented by errors that carry a server-provided
+// Retry-After hint.
+type RetryAfterer interface {
+	RetryAfter() (time.Duration, bool)
+}
+
+// Do runs fn, retrying with exponential backoff and jitter until it
+// succeeds, fn returns a terminal error (see Retryable), the policy's
+// attempt limit is reached, or ctx is cancelled.
+func Do(ctx context.Context, policy Policy, fn func() error) error {
+	limit := policy.Limit
+	if limit <= 0 {
+		limit = DefaultPolicy.Limit
+	}
+	backoff := policy.Backoff
+	if backoff <= 0 {
+		backoff = DefaultPolicy.Backoff
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < limit; attempt++ {
+		lastErr = fn()
+		if lastErr == nil {
+			return nil
+		}
+		if !Retryable(lastErr) {
+			return lastErr
+		}
+		if attempt == limit-1 {
+			break
+		}
+
+		wait := backoffWithJitter(backoff, attempt)
+		if d, ok := retryAfter(lastErr); ok {
+			wait = d
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", limit, lastErr)
+}
+
+// Retryable reports whether err looks transient (network hiccup, 429,
+// 502/503/504) as opposed to terminal (auth, other 4xx).
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var sc StatusCoder
+	if errors.As(err, &sc) {
+		if retryable, decided := classifyStatus(sc.StatusCode()); decided {
+			return retryable
+		}
+	} else if code, ok := apiStatusCode(err); ok {
+		if retryable, decided := classifyStatus(code); decided {
+			return retryable
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	// Unclassified errors (e.g. connection reset, DNS blip) are assumed
+	// transient so a single bad response doesn't fail the whole run.
+	return true
+}
+
+// classifyStatus reports whether code is retryable, and whether it was
+// decisive enough to classify at all — a 2xx/3xx/unrecognized code
+// leaves the decision to the caller's other checks.
+func classifyStatus(code int) (retryable bool, decided bool) {
+	switch {
+	case code == http.StatusTooManyRequests,
+		code == http.StatusBadGateway,
+		code == http.StatusServiceUnavailable,
+		code == http.StatusGatewayTimeout:
+		return true, true
+	case code >= 400 && code < 500:
+		return false, true
+	}
+	return false, false
+}
+
+func retryAfter(err error) (time.Duration, bool) {
+	var ra RetryAfterer
+	if errors.As(err, &ra) {
+		return ra.RetryAfter()
+	}
+	return 0, false
+}
+
+func backoffWithJitter(base time.Duration, attempt int) time.Duration {
+	d := base * time.Duration(int64(1)<<uint(attempt))
+	const cap = 5 * time.Minute
+	if d > cap || d <= 0 {
+		d = cap
+	}
+	jitter := time.Duration(rand.Int63n(int64(d)/2 + 1))
+	return d/2 + jitter
+}
@@ -0,0 +1,202 @@
+// Package scaletest turns the action into a load generator for
+// Codesphere: it burst-deploys ephemeral preview workspaces and
+// aggregates latency and error statistics so teams can validate plan
+// capacity before rolling out PR previews org-wide.
+package scaletest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Runnable is a scale-test workload that can be executed against an id
+// (e.g. a randomly named preview branch).
+type Runnable interface {
+	Run(ctx context.Context, id string, logs io.Writer) error
+}
+
+// Cleanable is implemented by workloads that leave state behind and
+// need to be torn down once a run completes, whether it succeeded or
+// not.
+type Cleanable interface {
+	Cleanup(ctx context.Context, id string) error
+}
+
+// PhaseRecorder is implemented by workloads that track timings for
+// named sub-phases of a single run (e.g. "deploy", "waitRunning",
+// "pipeline"). When work implements it, Run aggregates per-phase
+// percentiles into Report.Phases alongside the end-to-end Duration.
+type PhaseRecorder interface {
+	Phases(id string) map[string]time.Duration
+}
+
+// Result captures one runnable instance's outcome and latency.
+type Result struct {
+	Id       string                   `json:"id"`
+	Err      string                   `json:"error,omitempty"`
+	Duration time.Duration            `json:"durationNs"`
+	Phases   map[string]time.Duration `json:"phasesNs,omitempty"`
+}
+
+// PhasePercentiles aggregates latency percentiles for a single named
+// phase across a batch of runs.
+type PhasePercentiles struct {
+	P50 time.Duration `json:"p50Ns"`
+	P95 time.Duration `json:"p95Ns"`
+	P99 time.Duration `json:"p99Ns"`
+}
+
+// Report aggregates latency percentiles and error counts across a batch
+// of runs.
+type Report struct {
+	Count   int                         `json:"count"`
+	Errors  int                         `json:"errors"`
+	P50     time.Duration               `json:"p50Ns"`
+	P95     time.Duration               `json:"p95Ns"`
+	P99     time.Duration               `json:"p99Ns"`
+	Phases  map[string]PhasePercentiles `json:"phases,omitempty"`
+	Results []Result                    `json:"results"`
+}
+
+// Run fans work out across concurrency workers, each executing one of
+// count instances of work under its own randomly-suffixed id, cleaning
+// up (if work implements Cleanable) as soon as each instance finishes,
+// and aggregating the results into a Report.
+func Run(ctx context.Context, work Runnable, concurrency, count int, perRunTimeout time.Duration, logs io.Writer) Report {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	jobs := make(chan int, count)
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+
+	results := make([]Result, count)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = runOne(ctx, work, i, perRunTimeout, logs)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return aggregate(results)
+}
+
+func runOne(ctx context.Context, work Runnable, index int, perRunTimeout time.Duration, logs io.Writer) Result {
+	id := fmt.Sprintf("st-%d-%d", time.Now().UnixNano(), rand.Intn(1_000_000))
+
+	runCtx, cancel := context.WithTimeout(ctx, perRunTimeout)
+	start := time.Now()
+	err := work.Run(runCtx, id, logs)
+	duration := time.Since(start)
+	cancel()
+
+	if cleanable, ok := work.(Cleanable); ok {
+		cleanupCtx, cancelCleanup := context.WithTimeout(ctx, perRunTimeout)
+		if cerr := cleanable.Cleanup(cleanupCtx, id); cerr != nil {
+			fmt.Fprintf(logs, "[%s] cleanup failed: %v\n", id, cerr)
+		}
+		cancelCleanup()
+	}
+
+	res := Result{Id: id, Duration: duration}
+	if pr, ok := work.(PhaseRecorder); ok {
+		res.Phases = pr.Phases(id)
+	}
+	if err != nil {
+		res.Err = err.Error()
+		fmt.Fprintf(logs, "[%s] failed after %s: %v\n", id, duration, err)
+	} else {
+		fmt.Fprintf(logs, "[%s] completed in %s\n", id, duration)
+	}
+	return res
+}
+
+func aggregate(results []Result) Report {
+	durations := make([]time.Duration, len(results))
+	phaseDurations := make(map[string][]time.Duration)
+	errors := 0
+	for i, r := range results {
+		durations[i] = r.Duration
+		if r.Err != "" {
+			errors++
+		}
+		for phase, d := range r.Phases {
+			phaseDurations[phase] = append(phaseDurations[phase], d)
+		}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	var phases map[string]PhasePercentiles
+	if len(phaseDurations) > 0 {
+		phases = make(map[string]PhasePercentiles, len(phaseDurations))
+		for phase, ds := range phaseDurations {
+			sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+			phases[phase] = PhasePercentiles{
+				P50: percentile(ds, 0.50),
+				P95: percentile(ds, 0.95),
+				P99: percentile(ds, 0.99),
+			}
+		}
+	}
+
+	return Report{
+		Count:   len(results),
+		Errors:  errors,
+		P50:     percentile(durations, 0.50),
+		P95:     percentile(durations, 0.95),
+		P99:     percentile(durations, 0.99),
+		Phases:  phases,
+		Results: results,
+	}
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p * float64(len(sorted)-1))
+	return sorted[idx]
+}
+
+// MarkdownTable renders the report as a GitHub-flavored markdown table
+// suitable for GITHUB_STEP_SUMMARY, followed by a per-phase latency
+// breakdown when the workload reported one.
+func (r Report) MarkdownTable() string {
+	var b strings.Builder
+	fmt.Fprintf(&b,
+		"### 🧪 Scale Test Report\n\n"+
+			"| Metric | Value |\n|---|---|\n"+
+			"| Runs | %d |\n| Errors | %d |\n| p50 | %s |\n| p95 | %s |\n| p99 | %s |\n",
+		r.Count, r.Errors, r.P50, r.P95, r.P99,
+	)
+
+	if len(r.Phases) > 0 {
+		phaseNames := make([]string, 0, len(r.Phases))
+		for phase := range r.Phases {
+			phaseNames = append(phaseNames, phase)
+		}
+		sort.Strings(phaseNames)
+
+		b.WriteString("\n| Phase | p50 | p95 | p99 |\n|---|---|---|---|\n")
+		for _, phase := range phaseNames {
+			p := r.Phases[phase]
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", phase, p.P50, p.P95, p.P99)
+		}
+	}
+
+	return b.String()
+}
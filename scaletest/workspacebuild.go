@@ -0,0 +1,174 @@
+package scaletest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/codesphere-cloud/cs-go/api"
+
+	"github.com/alexvcodesphere/new-gh-action-test/pipeline"
+	"github.com/alexvcodesphere/new-gh-action-test/retry"
+)
+
+// Runner drives one end-to-end preview workspace build: deploy, wait for
+// it to come up, run the pipeline DAG, then delete it. It implements
+// Runnable, Cleanable and PhaseRecorder so Run can schedule it, tear it
+// down, and break its latency down into deploy/waitRunning/pipeline
+// phases like any other workload.
+type Runner struct {
+	Client      *api.Client
+	TeamId      int
+	PlanId      int
+	RepoUrl     string
+	Dag         pipeline.DAG
+	MaxParallel int
+	Policy      retry.Policy
+
+	mu     sync.Mutex
+	wsIds  map[string]int
+	phases map[string]map[string]time.Duration
+}
+
+// NewRunner builds a Runner that deploys workspaces against repoUrl and
+// schedules dag's stages on each one, honoring its "needs" edges the
+// same way the action's own pipeline mode does.
+func NewRunner(client *api.Client, teamId, planId int, repoUrl string, dag pipeline.DAG, maxParallel int, policy retry.Policy) *Runner {
+	return &Runner{
+		Client:      client,
+		TeamId:      teamId,
+		PlanId:      planId,
+		RepoUrl:     repoUrl,
+		Dag:         dag,
+		MaxParallel: maxParallel,
+		Policy:      policy,
+		wsIds:       make(map[string]int),
+		phases:      make(map[string]map[string]time.Duration),
+	}
+}
+
+// Run deploys a workspace named id on a matching preview branch, waits
+// for it to come up, and runs the pipeline against it, recording the
+// deploy/waitRunning/pipeline phase timings so Phases(id) can report
+// them afterward.
+func (r *Runner) Run(ctx context.Context, id string, logs io.Writer) error {
+	branch := fmt.Sprintf("scaletest/%s", id)
+	phases := make(map[string]time.Duration, 3)
+	defer func() { r.recordPhases(id, phases) }()
+
+	deployStart := time.Now()
+	ws, err := r.Client.DeployWorkspace(api.DeployWorkspaceArgs{
+		TeamId:        r.TeamId,
+		PlanId:        r.PlanId,
+		Name:          id,
+		IsPrivateRepo: true,
+		GitUrl:        &r.RepoUrl,
+		Branch:        &branch,
+		Timeout:       5 * time.Minute,
+	})
+	phases["deploy"] = time.Since(deployStart)
+	if err != nil {
+		return fmt.Errorf("deploy: %w", err)
+	}
+
+	r.mu.Lock()
+	r.wsIds[id] = ws.Id
+	r.mu.Unlock()
+
+	fmt.Fprintf(logs, "[%s] deployed workspace id=%d\n", id, ws.Id)
+
+	waitStart := time.Now()
+	err = r.Client.WaitForWorkspaceRunning(ws, 5*time.Minute)
+	phases["waitRunning"] = time.Since(waitStart)
+	if err != nil {
+		return fmt.Errorf("wait running: %w", err)
+	}
+
+	pipelineStart := time.Now()
+	err = pipeline.Run(ctx, r.Dag, r.MaxParallel, func(ctx context.Context, stage string) error {
+		return r.runStage(ctx, ws.Id, stage)
+	})
+	phases["pipeline"] = time.Since(pipelineStart)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// runStage starts a single stage and, unless it's the fire-and-forget
+// terminal 'run' stage, polls until it reaches a terminal state.
+func (r *Runner) runStage(ctx context.Context, wsId int, stage string) error {
+	if err := retry.Do(ctx, r.Policy, func() error {
+		return r.Client.StartPipelineStage(wsId, "", stage)
+	}); err != nil {
+		return fmt.Errorf("starting stage '%s': %w", stage, err)
+	}
+	if stage == "run" {
+		return nil
+	}
+	return r.pollStage(ctx, wsId, stage)
+}
+
+// recordPhases stashes id's phase timings so Phases(id) can return them
+// once Run has finished.
+func (r *Runner) recordPhases(id string, phases map[string]time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.phases[id] = phases
+}
+
+// Phases implements PhaseRecorder, returning the deploy/waitRunning/
+// pipeline timings Run recorded for id.
+func (r *Runner) Phases(id string) map[string]time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.phases[id]
+}
+
+func (r *Runner) pollStage(ctx context.Context, wsId int, stage string) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+
+		var statuses []api.PipelineStatus
+		err := retry.Do(ctx, r.Policy, func() error {
+			var err error
+			statuses, err = r.Client.GetPipelineState(wsId, stage)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("polling stage '%s': %w", stage, err)
+		}
+
+		allDone := true
+		for _, s := range statuses {
+			switch s.State {
+			case "failure", "aborted":
+				return fmt.Errorf("pipeline '%s' failed (state: %s)", stage, s.State)
+			case "success":
+			default:
+				allDone = false
+			}
+		}
+		if allDone && len(statuses) > 0 {
+			return nil
+		}
+	}
+}
+
+// Cleanup deletes the workspace that Run created for id.
+func (r *Runner) Cleanup(ctx context.Context, id string) error {
+	r.mu.Lock()
+	wsId, ok := r.wsIds[id]
+	r.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return r.Client.DeleteWorkspace(wsId)
+}
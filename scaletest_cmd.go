@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/codesphere-cloud/cs-go/api"
+
+	"github.com/alexvcodesphere/new-gh-action-test/pipeline"
+	"github.com/alexvcodesphere/new-gh-action-test/scaletest"
+)
+
+// runScaletest turns the action into a load generator: it burst-deploys
+// cfg.scaletestCount ephemeral preview workspaces across
+// cfg.scaletestConcurrency workers, tearing each one down as soon as it
+// finishes, then reports latency percentiles and error counts so a team
+// can validate plan capacity before rolling out PR previews org-wide.
+func runScaletest(ctx context.Context, client *api.Client, cfg *config) error {
+	logger.Info().Int("concurrency", cfg.scaletestConcurrency).Int("count", cfg.scaletestCount).Msg("running scaletest")
+
+	dag, err := pipeline.ParseStages(cfg.stagesRaw)
+	if err != nil {
+		return fmt.Errorf("parsing stages: %w", err)
+	}
+
+	runner := scaletest.NewRunner(client, cfg.teamId, cfg.planId, cfg.repoUrl, dag, cfg.maxParallel, cfg.retryPolicy)
+	report := scaletest.Run(ctx, runner, cfg.scaletestConcurrency, cfg.scaletestCount, cfg.scaletestTimeout, os.Stdout)
+
+	reportJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling report: %w", err)
+	}
+	fmt.Println(string(reportJSON))
+
+	if f := os.Getenv("GITHUB_STEP_SUMMARY"); f != "" {
+		appendToFile(f, report.MarkdownTable())
+	}
+
+	if report.Errors > 0 {
+		return fmt.Errorf("scaletest completed with %d/%d errors", report.Errors, report.Count)
+	}
+	return nil
+}
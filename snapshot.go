@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/codesphere-cloud/cs-go/api"
+)
+
+// defaultMaxArchiveBytes caps how large a workspace archive we'll round
+// -trip through ExecCommand. The Codesphere API has no dedicated
+// file-transfer endpoint — ExecCommand, which buffers its whole
+// stdout/command string rather than streaming, is the only primitive
+// available to pull bytes out of (or push them into) a workspace — so
+// unlike log streaming this can't scale to a multi-GB /home/user.
+const defaultMaxArchiveBytes = 64 * 1024 * 1024 // 64 MiB
+
+// exportWorkspace tars /home/user inside the workspace and returns it
+// decoded into memory. There's no export endpoint to call; this runs
+// `tar` via ExecCommand and base64-decodes its buffered stdout, which is
+// the only way the real API lets us pull file contents out of a
+// workspace.
+func exportWorkspace(client *api.Client, wsId int) (io.Reader, error) {
+	stdout, stderr, err := client.ExecCommand(wsId, "tar czf - /home/user 2>/dev/null | base64 -w0", "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("exec tar: %w", err)
+	}
+	if stderr != "" {
+		logger.Warn().Int("ws", wsId).Str("stderr", stderr).Msg("export command wrote to stderr")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(stdout))
+	if err != nil {
+		return nil, fmt.Errorf("decoding exported archive: %w", err)
+	}
+	if int64(len(decoded)) > defaultMaxArchiveBytes {
+		return nil, fmt.Errorf("workspace archive exceeds %d byte cap", defaultMaxArchiveBytes)
+	}
+	return bytes.NewReader(decoded), nil
+}
+
+// importWorkspace reads r (a tarball rooted at /, as exportWorkspace
+// produces) and extracts it into the workspace by round-tripping it
+// through ExecCommand as a base64 literal embedded in the command
+// string — again, the only transport the real API gives us.
+func importWorkspace(client *api.Client, wsId int, r io.Reader) error {
+	data, err := io.ReadAll(io.LimitReader(r, defaultMaxArchiveBytes+1))
+	if err != nil {
+		return fmt.Errorf("reading archive: %w", err)
+	}
+	if int64(len(data)) > defaultMaxArchiveBytes {
+		return fmt.Errorf("workspace archive exceeds %d byte cap", defaultMaxArchiveBytes)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	cmd := fmt.Sprintf("echo %s | base64 -d | tar xzf - -C /", encoded)
+	_, stderr, err := client.ExecCommand(wsId, cmd, "", nil)
+	if err != nil {
+		return fmt.Errorf("exec untar: %w", err)
+	}
+	if stderr != "" {
+		logger.Warn().Int("ws", wsId).Str("stderr", stderr).Msg("import command wrote to stderr")
+	}
+	return nil
+}